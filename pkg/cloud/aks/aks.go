@@ -0,0 +1,139 @@
+package aks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/log"
+)
+
+// Provider adapts Azure AD service principals to the cloud.ServiceAccountProvider interface so
+// they can be driven from `jx create cloud-service-account --provider aks`. Credentials are
+// either a service principal password or, when spec.KeyType is "federated-credential", a
+// federated identity credential trusting the cluster's OIDC issuer, which needs no secret at all.
+type Provider struct{}
+
+// NewProvider creates an AKS cloud.ServiceAccountProvider
+func NewProvider() cloud.ServiceAccountProvider {
+	return &Provider{}
+}
+
+type servicePrincipal struct {
+	AppId    string `json:"appId"`
+	Password string `json:"password"`
+	Tenant   string `json:"tenant"`
+}
+
+// EnsureAccount implements cloud.ServiceAccountProvider
+func (p *Provider) EnsureAccount(spec cloud.ServiceAccountSpec) (string, error) {
+	existing, err := p.findServicePrincipal(spec.Name)
+	if err != nil {
+		return "", err
+	}
+	if existing != "" {
+		if !spec.IfNotExists {
+			return "", fmt.Errorf("service principal %s already exists", spec.Name)
+		}
+		log.Infof("Service principal %s already exists, reusing it\n", spec.Name)
+		return existing, nil
+	}
+
+	out, err := exec.Command("az", "ad", "sp", "create-for-rbac", "--name", spec.Name, "--skip-assignment").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to create service principal %s: %s, %s", spec.Name, err, string(out))
+	}
+
+	var sp servicePrincipal
+	if err := json.Unmarshal(out, &sp); err != nil {
+		return "", fmt.Errorf("failed to parse service principal response for %s: %s", spec.Name, err)
+	}
+	return sp.AppId, nil
+}
+
+func (p *Provider) findServicePrincipal(name string) (string, error) {
+	out, err := exec.Command("az", "ad", "sp", "list", "--display-name", name, "--query", "[0].appId", "-o", "tsv").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up service principal %s: %s, %s", name, err, string(out))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// AttachRoles implements cloud.ServiceAccountProvider
+func (p *Provider) AttachRoles(identifier string, spec cloud.ServiceAccountSpec) error {
+	for _, role := range spec.Roles {
+		log.Infof("Assigning role %s to %s\n", role, identifier)
+		out, err := exec.Command("az", "role", "assignment", "create", "--assignee", identifier,
+			"--role", role, "--subscription", spec.Account).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to assign role %s to %s: %s, %s", role, identifier, err, string(out))
+		}
+	}
+	return nil
+}
+
+// IssueCredential implements cloud.ServiceAccountProvider. For "federated-credential" it binds
+// the Kubernetes service account as a federated identity and returns just the app id; for
+// "password" it resets the service principal's password and writes it to a JSON file under
+// homeDir - this reset is also what makes --key-rotate produce a fresh credential, since Rotate
+// itself is a no-op for "password" to avoid resetting (and so invalidating) it twice in one run.
+func (p *Provider) IssueCredential(identifier string, spec cloud.ServiceAccountSpec, homeDir string) (cloud.Credential, error) {
+	switch spec.KeyType {
+	case "", "none":
+		return cloud.Credential{Identifier: identifier}, nil
+	case "federated-credential":
+		if spec.OIDCProvider == "" || spec.K8sNamespace == "" || spec.K8sServiceAccount == "" {
+			return cloud.Credential{}, fmt.Errorf("--oidc-provider, --k8s-namespace and --k8s-sa are required for federated-credential")
+		}
+		subject := fmt.Sprintf("system:serviceaccount:%s:%s", spec.K8sNamespace, spec.K8sServiceAccount)
+		out, err := exec.Command("az", "ad", "app", "federated-credential", "create", "--id", identifier,
+			"--parameters", fmt.Sprintf(`{"name":"%s","issuer":"%s","subject":"%s","audiences":["api://AzureADTokenExchange"]}`,
+				spec.Name, spec.OIDCProvider, subject)).CombinedOutput()
+		if err != nil {
+			return cloud.Credential{}, fmt.Errorf("failed to create federated credential for %s: %s, %s", identifier, err, string(out))
+		}
+		return cloud.Credential{Identifier: identifier}, nil
+	case "password":
+		out, err := exec.Command("az", "ad", "sp", "credential", "reset", "--name", identifier, "-o", "json").CombinedOutput()
+		if err != nil {
+			return cloud.Credential{}, fmt.Errorf("failed to reset credential for %s: %s, %s", identifier, err, string(out))
+		}
+
+		var sp servicePrincipal
+		if err := json.Unmarshal(out, &sp); err != nil {
+			return cloud.Credential{}, fmt.Errorf("failed to parse credential reset response for %s: %s", identifier, err)
+		}
+
+		keyDir := filepath.Join(homeDir, ".azure")
+		if err := os.MkdirAll(keyDir, 0700); err != nil {
+			return cloud.Credential{}, err
+		}
+		keyPath := filepath.Join(keyDir, fmt.Sprintf("%s.key.json", spec.Name))
+		data, err := json.MarshalIndent(sp, "", "  ")
+		if err != nil {
+			return cloud.Credential{}, err
+		}
+		if err := ioutil.WriteFile(keyPath, data, 0600); err != nil {
+			return cloud.Credential{}, err
+		}
+		return cloud.Credential{KeyPath: keyPath, Identifier: identifier}, nil
+	default:
+		return cloud.Credential{}, fmt.Errorf("unsupported key type %s for provider aks", spec.KeyType)
+	}
+}
+
+// Rotate implements cloud.ServiceAccountProvider. Azure does not expose a credential age to check
+// against maxAge, and "az ad sp credential reset" both mints a new password and invalidates the
+// previous one in a single call, so for "password" it is a no-op: IssueCredential's own reset,
+// which always runs, already produces a fresh credential on every --key-rotate request. Doing the
+// reset here too would just throw away the credential it returned and invalidate it a second time
+// within the same command invocation.
+func (p *Provider) Rotate(identifier string, spec cloud.ServiceAccountSpec, maxAge time.Duration) error {
+	return nil
+}
@@ -0,0 +1,223 @@
+package eks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/log"
+)
+
+// Provider adapts AWS IAM users/roles to the cloud.ServiceAccountProvider interface so they can
+// be driven from `jx create cloud-service-account --provider eks`. Credentials are either a
+// long-lived IAM user access key or, when spec.KeyType is "irsa", an IAM role trusted by the
+// cluster's OIDC provider (IAM Roles for Service Accounts), which needs no downloaded key at all.
+type Provider struct{}
+
+// NewProvider creates an EKS cloud.ServiceAccountProvider
+func NewProvider() cloud.ServiceAccountProvider {
+	return &Provider{}
+}
+
+// EnsureAccount implements cloud.ServiceAccountProvider
+func (p *Provider) EnsureAccount(spec cloud.ServiceAccountSpec) (string, error) {
+	if spec.KeyType == "irsa" {
+		return p.ensureRole(spec)
+	}
+	return p.ensureUser(spec)
+}
+
+func (p *Provider) ensureUser(spec cloud.ServiceAccountSpec) (string, error) {
+	out, err := exec.Command("aws", "iam", "create-user", "--user-name", spec.Name).CombinedOutput()
+	if err != nil {
+		if !spec.IfNotExists || !strings.Contains(string(out), "EntityAlreadyExists") {
+			return "", fmt.Errorf("failed to create IAM user %s: %s, %s", spec.Name, err, string(out))
+		}
+		log.Infof("IAM user %s already exists, reusing it\n", spec.Name)
+	}
+	return fmt.Sprintf("arn:aws:iam::%s:user/%s", spec.Account, spec.Name), nil
+}
+
+func (p *Provider) ensureRole(spec cloud.ServiceAccountSpec) (string, error) {
+	if spec.OIDCProvider == "" || spec.K8sNamespace == "" || spec.K8sServiceAccount == "" {
+		return "", fmt.Errorf("--oidc-provider, --k8s-namespace and --k8s-sa are required for IRSA")
+	}
+
+	trustPolicy := irsaTrustPolicy(spec.Account, spec.OIDCProvider, spec.K8sNamespace, spec.K8sServiceAccount)
+	policyFile, err := writeTempFile(trustPolicy)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(policyFile)
+
+	out, err := exec.Command("aws", "iam", "create-role", "--role-name", spec.Name,
+		"--assume-role-policy-document", "file://"+policyFile).CombinedOutput()
+	if err != nil {
+		if !spec.IfNotExists || !strings.Contains(string(out), "EntityAlreadyExists") {
+			return "", fmt.Errorf("failed to create IAM role %s: %s, %s", spec.Name, err, string(out))
+		}
+		log.Infof("IAM role %s already exists, reusing it\n", spec.Name)
+	}
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", spec.Account, spec.Name), nil
+}
+
+// irsaTrustPolicy builds the assume-role-policy-document that lets the given Kubernetes service
+// account assume the IAM role via the cluster's OIDC provider
+func irsaTrustPolicy(account string, oidcProvider string, namespace string, serviceAccount string) string {
+	provider := strings.TrimPrefix(oidcProvider, "https://")
+	return fmt.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "Federated": "arn:aws:iam::%s:oidc-provider/%s"
+      },
+      "Action": "sts:AssumeRoleWithWebIdentity",
+      "Condition": {
+        "StringEquals": {
+          "%s:sub": "system:serviceaccount:%s:%s"
+        }
+      }
+    }
+  ]
+}`, account, provider, provider, namespace, serviceAccount)
+}
+
+// AttachRoles implements cloud.ServiceAccountProvider, attaching each policy ARN in spec.Roles
+// to the IAM user or role
+func (p *Provider) AttachRoles(identifier string, spec cloud.ServiceAccountSpec) error {
+	attach := "attach-user-policy"
+	nameFlag := "--user-name"
+	if spec.KeyType == "irsa" {
+		attach = "attach-role-policy"
+		nameFlag = "--role-name"
+	}
+
+	for _, policyArn := range spec.Roles {
+		log.Infof("Attaching policy %s to %s\n", policyArn, identifier)
+		out, err := exec.Command("aws", "iam", attach, nameFlag, spec.Name, "--policy-arn", policyArn).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to attach policy %s to %s: %s, %s", policyArn, identifier, err, string(out))
+		}
+	}
+	return nil
+}
+
+// accessKey is the subset of `aws iam create-access-key`/`list-access-keys` we need
+type accessKey struct {
+	AccessKeyId     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	CreateDate      string `json:"CreateDate"`
+}
+
+type createAccessKeyResponse struct {
+	AccessKey accessKey `json:"AccessKey"`
+}
+
+// IssueCredential implements cloud.ServiceAccountProvider. For "irsa" it returns just the role
+// ARN since pods authenticate via the federated Kubernetes service account with no key needed;
+// for "access-key" it mints an IAM access key and writes it to a JSON file
+// under homeDir. Unless spec.ForceNewKey is set, an access key already downloaded to homeDir is
+// reused instead of minting a new one: IAM only allows 2 access keys per user, and a secret key
+// can never be retrieved again after creation, so re-running this command against an existing
+// local key file must not call create-access-key a second time.
+func (p *Provider) IssueCredential(identifier string, spec cloud.ServiceAccountSpec, homeDir string) (cloud.Credential, error) {
+	switch spec.KeyType {
+	case "", "none":
+		return cloud.Credential{Identifier: identifier}, nil
+	case "irsa":
+		return cloud.Credential{Identifier: identifier}, nil
+	case "access-key":
+		keyDir := filepath.Join(homeDir, ".aws")
+		if err := os.MkdirAll(keyDir, 0700); err != nil {
+			return cloud.Credential{}, err
+		}
+		keyPath := filepath.Join(keyDir, fmt.Sprintf("%s.key.json", spec.Name))
+
+		if !spec.ForceNewKey {
+			if _, err := os.Stat(keyPath); err == nil {
+				return cloud.Credential{KeyPath: keyPath, Identifier: identifier}, nil
+			}
+		}
+
+		out, err := exec.Command("aws", "iam", "create-access-key", "--user-name", spec.Name, "--output", "json").CombinedOutput()
+		if err != nil {
+			return cloud.Credential{}, fmt.Errorf("failed to create access key for %s: %s, %s", identifier, err, string(out))
+		}
+
+		var resp createAccessKeyResponse
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return cloud.Credential{}, fmt.Errorf("failed to parse access key response for %s: %s", identifier, err)
+		}
+
+		data, err := json.MarshalIndent(resp.AccessKey, "", "  ")
+		if err != nil {
+			return cloud.Credential{}, err
+		}
+		if err := ioutil.WriteFile(keyPath, data, 0600); err != nil {
+			return cloud.Credential{}, err
+		}
+
+		return cloud.Credential{KeyPath: keyPath, Identifier: identifier}, nil
+	default:
+		return cloud.Credential{}, fmt.Errorf("unsupported key type %s for provider eks", spec.KeyType)
+	}
+}
+
+// Rotate implements cloud.ServiceAccountProvider, deleting access keys older than maxAge
+func (p *Provider) Rotate(identifier string, spec cloud.ServiceAccountSpec, maxAge time.Duration) error {
+	switch spec.KeyType {
+	case "", "none", "irsa":
+		return nil
+	case "access-key":
+		// falls through to the access key rotation below
+	default:
+		return fmt.Errorf("unsupported key type %s for provider eks", spec.KeyType)
+	}
+
+	out, err := exec.Command("aws", "iam", "list-access-keys", "--user-name", spec.Name, "--output", "json").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to list access keys for %s: %s, %s", identifier, err, string(out))
+	}
+
+	var resp struct {
+		AccessKeyMetadata []accessKey `json:"AccessKeyMetadata"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return fmt.Errorf("failed to parse access keys for %s: %s", identifier, err)
+	}
+
+	for _, key := range resp.AccessKeyMetadata {
+		created, err := time.Parse(time.RFC3339, key.CreateDate)
+		if err != nil || time.Since(created) <= maxAge {
+			continue
+		}
+
+		log.Infof("Deleting access key %s for %s created on %s\n", key.AccessKeyId, identifier, created.Format(time.RFC3339))
+		out, err := exec.Command("aws", "iam", "delete-access-key", "--user-name", spec.Name, "--access-key-id", key.AccessKeyId).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to delete access key %s for %s: %s, %s", key.AccessKeyId, identifier, err, string(out))
+		}
+	}
+	return nil
+}
+
+// writeTempFile writes content to a new temporary file and returns its path
+func writeTempFile(content string) (string, error) {
+	f, err := ioutil.TempFile("", "trust-policy-")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,251 @@
+package gke
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/log"
+)
+
+// requiredProjectAPIs are the GCP APIs enabled on any newly created project so that it can be
+// used as a target for a Jenkins X cluster
+var requiredProjectAPIs = []string{
+	"iam.googleapis.com",
+	"compute.googleapis.com",
+	"container.googleapis.com",
+}
+
+// GetGoogleProjects returns the list of Google Cloud Project ids the current user has access to
+func GetGoogleProjects() ([]string, error) {
+	out, err := exec.Command("gcloud", "projects", "list", "--format=value(projectId)").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Google Cloud Projects: %s, %s", err, string(out))
+	}
+	var projects []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			projects = append(projects, line)
+		}
+	}
+	return projects, nil
+}
+
+// CreateGoogleProject creates a new Google Cloud Project with the given id and name, links the
+// given billing account (if any) and enables the APIs required to run a Jenkins X cluster. It
+// returns the id of the newly created project.
+func CreateGoogleProject(id string, name string, billingAccount string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("no project id specified")
+	}
+	if name == "" {
+		name = id
+	}
+
+	log.Infof("Creating Google Cloud Project %s\n", id)
+	args := []string{"projects", "create", id, "--name", name}
+	out, err := exec.Command("gcloud", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to create Google Cloud Project %s: %s, %s", id, err, string(out))
+	}
+
+	if err := waitForProjectActive(id, 5*time.Minute); err != nil {
+		return "", err
+	}
+
+	if billingAccount != "" {
+		log.Infof("Linking billing account %s to project %s\n", billingAccount, id)
+		out, err := exec.Command("gcloud", "beta", "billing", "projects", "link", id, "--billing-account", billingAccount).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("failed to link billing account %s to project %s: %s, %s", billingAccount, id, err, string(out))
+		}
+	}
+
+	for _, api := range requiredProjectAPIs {
+		log.Infof("Enabling API %s on project %s\n", api, id)
+		out, err := exec.Command("gcloud", "services", "enable", api, "--project", id).CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("failed to enable API %s on project %s: %s, %s", api, id, err, string(out))
+		}
+	}
+
+	return id, nil
+}
+
+// waitForProjectActive polls the Cloud Resource Manager API until the given project reaches the
+// ACTIVE lifecycle state or the timeout is reached
+func waitForProjectActive(id string, timeout time.Duration) error {
+	end := time.Now().Add(timeout)
+	for {
+		out, err := exec.Command("gcloud", "projects", "describe", id, "--format=value(lifecycleState)").CombinedOutput()
+		if err == nil && strings.TrimSpace(string(out)) == "ACTIVE" {
+			return nil
+		}
+		if time.Now().After(end) {
+			return fmt.Errorf("timed out waiting for project %s to become active", id)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// ServiceAccountEmail returns the IAM email address of the given service account in the given project
+func ServiceAccountEmail(serviceAccount string, projectId string) string {
+	return fmt.Sprintf("%s@%s.iam.gserviceaccount.com", serviceAccount, projectId)
+}
+
+// EnsureServiceAccount creates the given service account in the given project if it does not
+// already exist. If ifNotExists is true then the service account already existing (IAM returns
+// ALREADY_EXISTS) is not treated as an error.
+func EnsureServiceAccount(serviceAccount string, projectId string, ifNotExists bool) error {
+	out, err := exec.Command("gcloud", "iam", "service-accounts", "create", serviceAccount,
+		"--project", projectId, "--display-name", serviceAccount).CombinedOutput()
+	if err != nil {
+		if !ifNotExists || !strings.Contains(string(out), "ALREADY_EXISTS") {
+			return fmt.Errorf("failed to create service account %s: %s, %s", serviceAccount, err, string(out))
+		}
+		log.Infof("Service account %s already exists, reusing it\n", serviceAccount)
+	}
+	return nil
+}
+
+// GetOrCreateServiceAccount returns the file name of a JSON key for the given service account,
+// creating the service account in the given project if it does not already exist. If
+// ifNotExists is true then the service account already existing (IAM returns ALREADY_EXISTS) is
+// not treated as an error and key generation proceeds as normal.
+func GetOrCreateServiceAccount(serviceAccount string, projectId string, homeDir string, ifNotExists bool) (string, error) {
+	path, _, err := CreateServiceAccountKey(serviceAccount, projectId, homeDir, ifNotExists, "json", false)
+	return path, err
+}
+
+// CreateServiceAccountKey returns the file name of a key of the given keyType ("json" or "p12")
+// for the given service account, creating the service account in the given project if it does
+// not already exist. Unless forceNew is set, an existing key already downloaded to disk is
+// reused instead of minting a new one; forceNew must be set immediately after a rotation has
+// revoked the previous keys, since otherwise the stale local file would be handed back as if it
+// were still valid. The cache check also looks for "<keyPath>.enc", since cloud.EncryptKeyFile
+// shreds the plaintext once it has encrypted it — the second return value reports whether the
+// file found on disk was that encrypted form, so callers know not to encrypt it again.
+func CreateServiceAccountKey(serviceAccount string, projectId string, homeDir string, ifNotExists bool, keyType string, forceNew bool) (string, bool, error) {
+	if keyType != "json" && keyType != "p12" {
+		return "", false, fmt.Errorf("unsupported key type %s, must be json or p12", keyType)
+	}
+
+	serviceAccountDir := filepath.Join(homeDir, ".gcp")
+	err := os.MkdirAll(serviceAccountDir, 0700)
+	if err != nil {
+		return "", false, err
+	}
+
+	keyPath := filepath.Join(serviceAccountDir, fmt.Sprintf("%s.key.%s", serviceAccount, keyType))
+	if !forceNew {
+		if _, err := os.Stat(keyPath + ".enc"); err == nil {
+			return keyPath + ".enc", true, nil
+		}
+		if _, err := os.Stat(keyPath); err == nil {
+			return keyPath, false, nil
+		}
+	}
+
+	if err := EnsureServiceAccount(serviceAccount, projectId, ifNotExists); err != nil {
+		return "", false, err
+	}
+
+	email := ServiceAccountEmail(serviceAccount, projectId)
+	out, err := exec.Command("gcloud", "iam", "service-accounts", "keys", "create", keyPath,
+		"--iam-account", email, "--key-file-type", keyType).CombinedOutput()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create key for service account %s: %s, %s", serviceAccount, err, string(out))
+	}
+
+	return keyPath, false, nil
+}
+
+// BindWorkloadIdentityUser binds the GCP service account to a Kubernetes service account so that
+// workloads running as the Kubernetes service account can authenticate as the GCP service
+// account without a downloaded key, per https://cloud.google.com/kubernetes-engine/docs/how-to/workload-identity
+func BindWorkloadIdentityUser(serviceAccount string, projectId string, k8sNamespace string, k8sServiceAccount string) error {
+	email := ServiceAccountEmail(serviceAccount, projectId)
+	member := fmt.Sprintf("serviceAccount:%s.svc.id.goog[%s/%s]", projectId, k8sNamespace, k8sServiceAccount)
+
+	log.Infof("Binding %s to workload identity user %s\n", email, member)
+	out, err := exec.Command("gcloud", "iam", "service-accounts", "add-iam-policy-binding", email,
+		"--role", "roles/iam.workloadIdentityUser", "--member", member).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to bind workload identity user %s to service account %s: %s, %s", member, email, err, string(out))
+	}
+	return nil
+}
+
+// serviceAccountKey is the subset of `gcloud iam service-accounts keys list --format=json` we
+// care about when deciding which keys to rotate
+type serviceAccountKey struct {
+	Name           string `json:"name"`
+	ValidAfterTime string `json:"validAfterTime"`
+}
+
+// RotateServiceAccountKeys deletes any user-managed key on the given service account older than
+// maxAge; keys younger than maxAge are left in place
+func RotateServiceAccountKeys(serviceAccount string, projectId string, maxAge time.Duration) error {
+	email := ServiceAccountEmail(serviceAccount, projectId)
+
+	out, err := exec.Command("gcloud", "iam", "service-accounts", "keys", "list",
+		"--iam-account", email, "--managed-by", "user", "--format=json").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to list keys for service account %s: %s, %s", email, err, string(out))
+	}
+
+	var keys []serviceAccountKey
+	if err := json.Unmarshal(out, &keys); err != nil {
+		return fmt.Errorf("failed to parse keys for service account %s: %s", email, err)
+	}
+
+	for _, key := range expiredKeys(keys, maxAge) {
+		validAfter, _ := time.Parse(time.RFC3339, key.ValidAfterTime)
+		log.Infof("Deleting key %s for service account %s created on %s\n", key.Name, email, validAfter.Format(time.RFC3339))
+		out, err := exec.Command("gcloud", "iam", "service-accounts", "keys", "delete", key.Name,
+			"--iam-account", email, "--quiet").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to delete key %s for service account %s: %s, %s", key.Name, email, err, string(out))
+		}
+	}
+
+	return nil
+}
+
+// expiredKeys returns the keys whose validAfterTime is older than maxAge, skipping any whose
+// validAfterTime fails to parse since gcloud should never produce one we can't read
+func expiredKeys(keys []serviceAccountKey, maxAge time.Duration) []serviceAccountKey {
+	var expired []serviceAccountKey
+	for _, key := range keys {
+		validAfter, err := time.Parse(time.RFC3339, key.ValidAfterTime)
+		if err != nil {
+			continue
+		}
+		if time.Since(validAfter) <= maxAge {
+			continue
+		}
+		expired = append(expired, key)
+	}
+	return expired
+}
+
+// BindServiceAccountRoles binds each of the given IAM roles to the service account on the given
+// project, e.g. "roles/container.admin"
+func BindServiceAccountRoles(serviceAccount string, projectId string, roles []string) error {
+	email := ServiceAccountEmail(serviceAccount, projectId)
+
+	for _, role := range roles {
+		log.Infof("Binding role %s to service account %s\n", role, email)
+		out, err := exec.Command("gcloud", "projects", "add-iam-policy-binding", projectId,
+			"--member", fmt.Sprintf("serviceAccount:%s", email), "--role", role).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to bind role %s to service account %s: %s, %s", role, email, err, string(out))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package gke
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiredKeys(t *testing.T) {
+	now := time.Now()
+	keys := []serviceAccountKey{
+		{Name: "fresh", ValidAfterTime: now.Add(-1 * time.Hour).Format(time.RFC3339)},
+		{Name: "stale", ValidAfterTime: now.Add(-100 * 24 * time.Hour).Format(time.RFC3339)},
+		{Name: "unparseable", ValidAfterTime: "not-a-time"},
+	}
+
+	expired := expiredKeys(keys, 90*24*time.Hour)
+
+	if len(expired) != 1 {
+		t.Fatalf("expected 1 expired key, got %d: %v", len(expired), expired)
+	}
+	if expired[0].Name != "stale" {
+		t.Errorf("expected the stale key to be rotated, got %s", expired[0].Name)
+	}
+}
+
+func TestExpiredKeysNoneOverMaxAge(t *testing.T) {
+	now := time.Now()
+	keys := []serviceAccountKey{
+		{Name: "a", ValidAfterTime: now.Add(-1 * time.Hour).Format(time.RFC3339)},
+		{Name: "b", ValidAfterTime: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+	}
+
+	if expired := expiredKeys(keys, 90*24*time.Hour); len(expired) != 0 {
+		t.Errorf("expected no expired keys, got %v", expired)
+	}
+}
@@ -0,0 +1,68 @@
+package gke
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/jx/pkg/cloud"
+)
+
+// Provider adapts the GKE service account functions to the cloud.ServiceAccountProvider
+// interface so they can be driven from `jx create cloud-service-account --provider gke`
+type Provider struct{}
+
+// NewProvider creates a GKE cloud.ServiceAccountProvider
+func NewProvider() cloud.ServiceAccountProvider {
+	return &Provider{}
+}
+
+// EnsureAccount implements cloud.ServiceAccountProvider
+func (p *Provider) EnsureAccount(spec cloud.ServiceAccountSpec) (string, error) {
+	if err := EnsureServiceAccount(spec.Name, spec.Account, spec.IfNotExists); err != nil {
+		return "", err
+	}
+	return ServiceAccountEmail(spec.Name, spec.Account), nil
+}
+
+// AttachRoles implements cloud.ServiceAccountProvider
+func (p *Provider) AttachRoles(identifier string, spec cloud.ServiceAccountSpec) error {
+	if len(spec.Roles) == 0 {
+		return nil
+	}
+	return BindServiceAccountRoles(spec.Name, spec.Account, spec.Roles)
+}
+
+// IssueCredential implements cloud.ServiceAccountProvider
+func (p *Provider) IssueCredential(identifier string, spec cloud.ServiceAccountSpec, homeDir string) (cloud.Credential, error) {
+	switch spec.KeyType {
+	case "", "none":
+		return cloud.Credential{Identifier: identifier}, nil
+	case "workload-identity":
+		if spec.K8sNamespace == "" || spec.K8sServiceAccount == "" {
+			return cloud.Credential{}, fmt.Errorf("k8s namespace and service account are required for workload-identity")
+		}
+		if err := BindWorkloadIdentityUser(spec.Name, spec.Account, spec.K8sNamespace, spec.K8sServiceAccount); err != nil {
+			return cloud.Credential{}, err
+		}
+		return cloud.Credential{Identifier: identifier}, nil
+	case "json", "p12":
+		path, alreadyEncrypted, err := CreateServiceAccountKey(spec.Name, spec.Account, homeDir, spec.IfNotExists, spec.KeyType, spec.ForceNewKey)
+		if err != nil {
+			return cloud.Credential{}, err
+		}
+		if !alreadyEncrypted && (spec.KmsKey != "" || spec.AgeRecipient != "") {
+			path, err = cloud.EncryptKeyFile(path, spec.KmsKey, spec.AgeRecipient)
+			if err != nil {
+				return cloud.Credential{}, err
+			}
+		}
+		return cloud.Credential{KeyPath: path, Identifier: identifier}, nil
+	default:
+		return cloud.Credential{}, fmt.Errorf("unsupported key type %s for provider gke", spec.KeyType)
+	}
+}
+
+// Rotate implements cloud.ServiceAccountProvider
+func (p *Provider) Rotate(identifier string, spec cloud.ServiceAccountSpec, maxAge time.Duration) error {
+	return RotateServiceAccountKeys(spec.Name, spec.Account, maxAge)
+}
@@ -0,0 +1,77 @@
+package cloud
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EncryptKeyFile wraps the plaintext key at plaintextPath with Cloud KMS (when kmsKey is set) or
+// an age public key recipient (when ageRecipient is set), writes the ciphertext alongside it as
+// "<plaintextPath>.enc" and shreds the plaintext. Exactly one of kmsKey or ageRecipient must be
+// set. It returns the path of the ciphertext file. This is provider agnostic: it operates on any
+// downloaded credential file, not just GKE's.
+func EncryptKeyFile(plaintextPath string, kmsKey string, ageRecipient string) (string, error) {
+	if (kmsKey == "") == (ageRecipient == "") {
+		return "", fmt.Errorf("exactly one of --kms-key or --age-recipient must be specified")
+	}
+
+	encPath := plaintextPath + ".enc"
+
+	var out []byte
+	var err error
+	if kmsKey != "" {
+		out, err = exec.Command("gcloud", "kms", "encrypt", "--key", kmsKey,
+			"--plaintext-file", plaintextPath, "--ciphertext-file", encPath).CombinedOutput()
+	} else {
+		out, err = exec.Command("age", "-r", ageRecipient, "-o", encPath, plaintextPath).CombinedOutput()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %s, %s", plaintextPath, err, string(out))
+	}
+
+	if err := shredFile(plaintextPath); err != nil {
+		return "", err
+	}
+
+	return encPath, nil
+}
+
+// DecryptKeyFile decrypts the ciphertext at encPath, written by EncryptKeyFile, and writes the
+// plaintext key to w. Exactly one of kmsKey or ageIdentityFile must be set.
+func DecryptKeyFile(encPath string, kmsKey string, ageIdentityFile string, w io.Writer) error {
+	if (kmsKey == "") == (ageIdentityFile == "") {
+		return fmt.Errorf("exactly one of --kms-key or --age-identity must be specified")
+	}
+
+	var cmd *exec.Cmd
+	if kmsKey != "" {
+		cmd = exec.Command("gcloud", "kms", "decrypt", "--key", kmsKey,
+			"--ciphertext-file", encPath, "--plaintext-file", "-")
+	} else {
+		cmd = exec.Command("age", "-d", "-i", ageIdentityFile, encPath)
+	}
+
+	cmd.Stdout = w
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to decrypt %s: %s, %s", encPath, err, stderr.String())
+	}
+	return nil
+}
+
+// shredFile overwrites and removes the file at path so that its plaintext contents are not left
+// recoverable on disk, falling back to a plain remove if the `shred` tool is not available
+func shredFile(path string) error {
+	if _, err := exec.LookPath("shred"); err == nil {
+		out, err := exec.Command("shred", "-u", path).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to shred %s: %s, %s", path, err, string(out))
+		}
+		return nil
+	}
+	return os.Remove(path)
+}
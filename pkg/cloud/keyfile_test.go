@@ -0,0 +1,21 @@
+package cloud
+
+import "testing"
+
+func TestEncryptKeyFileRequiresExactlyOneRecipient(t *testing.T) {
+	if _, err := EncryptKeyFile("key.json", "", ""); err == nil {
+		t.Error("expected an error when neither --kms-key nor --age-recipient is set")
+	}
+	if _, err := EncryptKeyFile("key.json", "projects/p/locations/l/keyRings/r/cryptoKeys/k", "age1recipient"); err == nil {
+		t.Error("expected an error when both --kms-key and --age-recipient are set")
+	}
+}
+
+func TestDecryptKeyFileRequiresExactlyOneRecipient(t *testing.T) {
+	if err := DecryptKeyFile("key.json.enc", "", "", nil); err == nil {
+		t.Error("expected an error when neither --kms-key nor --age-identity is set")
+	}
+	if err := DecryptKeyFile("key.json.enc", "projects/p/locations/l/keyRings/r/cryptoKeys/k", "/tmp/age.key", nil); err == nil {
+		t.Error("expected an error when both --kms-key and --age-identity are set")
+	}
+}
@@ -0,0 +1,97 @@
+package cloud
+
+import "time"
+
+// ServiceAccountSpec describes a service account (or equivalent machine identity) to provision
+// in a cloud provider, independent of which cloud it targets
+type ServiceAccountSpec struct {
+	// Name is the name of the service account to create
+	Name string
+
+	// Account is the cloud-specific account the service account lives in: a GCP project id,
+	// an AWS account id, or an Azure subscription id
+	Account string
+
+	// IfNotExists makes EnsureAccount reuse an existing service account of the same name
+	// rather than failing
+	IfNotExists bool
+
+	// Roles are the provider-specific roles/policies to attach to the service account
+	Roles []string
+
+	// KeyType selects the kind of credential IssueCredential returns, e.g. "json", "p12",
+	// "workload-identity"/"irsa"/"federated-credential" or "none"
+	KeyType string
+
+	// K8sNamespace and K8sServiceAccount identify the Kubernetes service account to federate
+	// with, used when KeyType binds a workload identity rather than downloading a key
+	K8sNamespace      string
+	K8sServiceAccount string
+
+	// OIDCProvider is the cluster's OIDC issuer URL, required by EKS IRSA and AKS federated
+	// credentials to trust the Kubernetes service account
+	OIDCProvider string
+
+	// ForceNewKey tells IssueCredential to mint a brand new key instead of reusing one already
+	// cached on disk, e.g. immediately after Rotate has revoked the old ones
+	ForceNewKey bool
+
+	// KmsKey and AgeRecipient, when set, tell providers that support it to encrypt a downloaded
+	// key with Cloud KMS or an age public key recipient before returning it. At most one may be set.
+	KmsKey       string
+	AgeRecipient string
+}
+
+// Credential is the outcome of IssueCredential: either a downloaded key file or, for
+// workload-identity style bindings, just the identifier of what was bound
+type Credential struct {
+	// KeyPath is the path of a downloaded key file, empty when no key was downloaded
+	KeyPath string
+
+	// Identifier is the provider-specific identity of the service account, e.g. a GCP service
+	// account email, an AWS IAM user/role ARN or an Azure AD application id
+	Identifier string
+}
+
+// ServiceAccountProvider provisions and manages a machine identity in a single cloud provider.
+// GKE, EKS and AKS each implement this so that `jx create cloud-service-account` can drive all
+// three through one code path.
+type ServiceAccountProvider interface {
+	// EnsureAccount creates the service account described by spec if it does not already exist
+	// and returns its provider-specific identifier
+	EnsureAccount(spec ServiceAccountSpec) (string, error)
+
+	// AttachRoles binds spec.Roles to the service account identified by identifier
+	AttachRoles(identifier string, spec ServiceAccountSpec) error
+
+	// IssueCredential returns a usable credential for the service account, downloading a key
+	// file under homeDir or binding a workload identity, depending on spec.KeyType
+	IssueCredential(identifier string, spec ServiceAccountSpec, homeDir string) (Credential, error)
+
+	// Rotate deletes any credential on the service account older than maxAge
+	Rotate(identifier string, spec ServiceAccountSpec, maxAge time.Duration) error
+}
+
+// CreateServiceAccount drives a ServiceAccountProvider through the full EnsureAccount ->
+// AttachRoles -> (Rotate) -> IssueCredential sequence common to every provider, so that
+// `jx create cloud-service-account` and the provider-specific aliases such as
+// `jx create gke-service-account` provision a service account identically and can't drift apart
+// as flags are added. Rotate only runs when keyRotate is set, mirroring spec.ForceNewKey.
+func CreateServiceAccount(provider ServiceAccountProvider, spec ServiceAccountSpec, keyRotate bool, maxAge time.Duration, homeDir string) (Credential, error) {
+	identifier, err := provider.EnsureAccount(spec)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	if err := provider.AttachRoles(identifier, spec); err != nil {
+		return Credential{}, err
+	}
+
+	if keyRotate {
+		if err := provider.Rotate(identifier, spec, maxAge); err != nil {
+			return Credential{}, err
+		}
+	}
+
+	return provider.IssueCredential(identifier, spec, homeDir)
+}
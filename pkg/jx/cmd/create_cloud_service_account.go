@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/cloud/aks"
+	"github.com/jenkins-x/jx/pkg/cloud/eks"
+	"github.com/jenkins-x/jx/pkg/cloud/gke"
+	"github.com/jenkins-x/jx/pkg/jx/cmd/templates"
+	"github.com/jenkins-x/jx/pkg/log"
+	"github.com/jenkins-x/jx/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// validCloudServiceAccountProviders are the cloud providers supported by --provider
+var validCloudServiceAccountProviders = []string{"gke", "eks", "aks"}
+
+// validCloudServiceAccountKeyTypes are the --key-type values each --provider accepts, checked up
+// front so a typo fails before EnsureAccount/AttachRoles have made any real changes in the cloud
+// account, rather than deep inside IssueCredential's default case afterwards
+var validCloudServiceAccountKeyTypes = map[string][]string{
+	"gke": {"json", "p12", "workload-identity", "none"},
+	"eks": {"access-key", "irsa", "none"},
+	"aks": {"password", "federated-credential", "none"},
+}
+
+// CloudServiceAccountResult is emitted on stdout when --output json or --output yaml is used
+type CloudServiceAccountResult struct {
+	Provider   string   `json:"provider"`
+	Identifier string   `json:"identifier"`
+	KeyPath    string   `json:"keyPath"`
+	Account    string   `json:"account"`
+	Roles      []string `json:"roles"`
+}
+
+type CreateCloudServiceAccountFlags struct {
+	Provider          string
+	Name              string
+	Account           string
+	IfNotExists       bool
+	Roles             []string
+	RolesPreset       string
+	KeyType           string
+	K8sNamespace      string
+	K8sServiceAccount string
+	OIDCProvider      string
+	KeyRotate         bool
+	KeyMaxAge         time.Duration
+	KmsKey            string
+	AgeRecipient      string
+	Output            string
+}
+
+type CreateCloudServiceAccountOptions struct {
+	CreateOptions
+	Flags CreateCloudServiceAccountFlags
+}
+
+var (
+	createCloudServiceAccountExample = templates.Examples(`
+		# provision a GKE service account with a downloaded JSON key
+		jx create cloud-service-account --provider gke --name my-sa --account my-gke-project --key-type json
+
+		# provision an EKS IAM role trusted via IRSA, no key downloaded
+		jx create cloud-service-account --provider eks --name my-sa --account 123456789012 \
+			--key-type irsa --oidc-provider https://oidc.eks.us-east-1.amazonaws.com/id/XXXX \
+			--k8s-namespace jx --k8s-sa jx-sa --role arn:aws:iam::aws:policy/AmazonS3FullAccess
+
+		# provision an Azure AD service principal
+		jx create cloud-service-account --provider aks --name my-sa --account 00000000-0000-0000-0000-000000000000 --key-type password
+
+`)
+)
+
+// NewCmdCreateCloudServiceAccount creates a command object for the "create" command
+func NewCmdCreateCloudServiceAccount(f Factory, out io.Writer, errOut io.Writer) *cobra.Command {
+	options := &CreateCloudServiceAccountOptions{
+		CreateOptions: CreateOptions{
+			CommonOptions: CommonOptions{
+				Factory: f,
+				Out:     out,
+				Err:     errOut,
+			},
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:     "cloud-service-account",
+		Short:   "Creates a service account in GKE, EKS or AKS",
+		Example: createCloudServiceAccountExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			options.Cmd = cmd
+			options.Args = args
+			err := options.Run()
+			CheckErr(err)
+		},
+	}
+
+	options.addCommonFlags(cmd)
+	options.addFlags(cmd)
+
+	return cmd
+}
+
+func (options *CreateCloudServiceAccountOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&options.Flags.Provider, "provider", "", "", "The cloud provider to create the service account in: gke, eks or aks")
+	cmd.Flags().StringVarP(&options.Flags.Name, "name", "n", "", "The name of the service account to create")
+	cmd.Flags().StringVarP(&options.Flags.Account, "account", "a", "", "The cloud-specific account the service account lives in: a GCP project id, an AWS account id or an Azure subscription id")
+	cmd.Flags().BoolVarP(&options.Flags.IfNotExists, "if-not-exists", "", true, "Reuse the service account if one with the given name already exists instead of failing")
+	cmd.Flags().StringArrayVarP(&options.Flags.Roles, "role", "", nil, "A provider-specific role/policy to attach to the service account. Can be repeated")
+	cmd.Flags().StringVarP(&options.Flags.RolesPreset, "roles-preset", "", "", "A curated set of IAM roles to bind to the service account: jx-minimal, jx-full or kaniko. Only supported with --provider gke")
+	cmd.Flags().StringVarP(&options.Flags.KeyType, "key-type", "", "none", "The type of credential to issue, provider specific: gke supports json, p12, workload-identity, none; eks supports access-key, irsa, none; aks supports password, federated-credential, none")
+	cmd.Flags().StringVarP(&options.Flags.K8sNamespace, "k8s-namespace", "", "", "The Kubernetes namespace of the Kubernetes service account to federate with")
+	cmd.Flags().StringVarP(&options.Flags.K8sServiceAccount, "k8s-sa", "", "", "The Kubernetes service account to federate with")
+	cmd.Flags().StringVarP(&options.Flags.OIDCProvider, "oidc-provider", "", "", "The cluster's OIDC issuer URL, required by eks --key-type irsa and aks --key-type federated-credential")
+	cmd.Flags().BoolVarP(&options.Flags.KeyRotate, "key-rotate", "", false, "Delete keys older than --key-max-age before issuing a new one")
+	cmd.Flags().DurationVarP(&options.Flags.KeyMaxAge, "key-max-age", "", 90*24*time.Hour, "The maximum age of a service account key before --key-rotate deletes it")
+	cmd.Flags().StringVarP(&options.Flags.KmsKey, "kms-key", "", "", "A Cloud KMS key resource name used to encrypt the downloaded key, e.g. projects/p/locations/l/keyRings/r/cryptoKeys/k. Only supported with --provider gke")
+	cmd.Flags().StringVarP(&options.Flags.AgeRecipient, "age-recipient", "", "", "An age public key recipient used to encrypt the downloaded key. Only supported with --provider gke")
+	cmd.Flags().StringVarP(&options.Flags.Output, "output", "o", "text", "The output format of the created service account details: text, json or yaml")
+}
+
+// Run implements this command
+func (o *CreateCloudServiceAccountOptions) Run() error {
+	if !util.Contains(validCloudServiceAccountProviders, o.Flags.Provider) {
+		return fmt.Errorf("invalid --provider %s, must be one of %s", o.Flags.Provider, strings.Join(validCloudServiceAccountProviders, ", "))
+	}
+	if !util.Contains(validGkeServiceAccountOutputs, o.Flags.Output) {
+		return fmt.Errorf("invalid --output %s, must be one of %s", o.Flags.Output, strings.Join(validGkeServiceAccountOutputs, ", "))
+	}
+	if !util.Contains(validCloudServiceAccountKeyTypes[o.Flags.Provider], o.Flags.KeyType) {
+		return fmt.Errorf("invalid --key-type %s for --provider %s, must be one of %s", o.Flags.KeyType, o.Flags.Provider, strings.Join(validCloudServiceAccountKeyTypes[o.Flags.Provider], ", "))
+	}
+	if o.Flags.Name == "" {
+		return errors.New("--name must be specified")
+	}
+	if o.Flags.Account == "" {
+		return errors.New("--account must be specified")
+	}
+	if o.Flags.RolesPreset != "" && o.Flags.Provider != "gke" {
+		return errors.New("--roles-preset is only supported with --provider gke")
+	}
+	if o.Flags.KmsKey != "" && o.Flags.AgeRecipient != "" {
+		return errors.New("only one of --kms-key or --age-recipient may be specified")
+	}
+	if (o.Flags.KmsKey != "" || o.Flags.AgeRecipient != "") && o.Flags.Provider != "gke" {
+		return errors.New("--kms-key and --age-recipient are only supported with --provider gke")
+	}
+
+	provider, err := o.provider()
+	if err != nil {
+		return err
+	}
+
+	roles, err := resolveServiceAccountRoles(o.Flags.Roles, o.Flags.RolesPreset)
+	if err != nil {
+		return err
+	}
+
+	spec := cloud.ServiceAccountSpec{
+		Name:              o.Flags.Name,
+		Account:           o.Flags.Account,
+		IfNotExists:       o.Flags.IfNotExists,
+		Roles:             roles,
+		KeyType:           o.Flags.KeyType,
+		K8sNamespace:      o.Flags.K8sNamespace,
+		K8sServiceAccount: o.Flags.K8sServiceAccount,
+		OIDCProvider:      o.Flags.OIDCProvider,
+		ForceNewKey:       o.Flags.KeyRotate,
+		KmsKey:            o.Flags.KmsKey,
+		AgeRecipient:      o.Flags.AgeRecipient,
+	}
+
+	credential, err := cloud.CreateServiceAccount(provider, spec, o.Flags.KeyRotate, o.Flags.KeyMaxAge, util.HomeDir())
+	if err != nil {
+		return err
+	}
+
+	return o.renderResult(credential, roles)
+}
+
+// provider resolves --provider to its cloud.ServiceAccountProvider implementation
+func (o *CreateCloudServiceAccountOptions) provider() (cloud.ServiceAccountProvider, error) {
+	switch o.Flags.Provider {
+	case "gke":
+		return gke.NewProvider(), nil
+	case "eks":
+		return eks.NewProvider(), nil
+	case "aks":
+		return aks.NewProvider(), nil
+	default:
+		return nil, fmt.Errorf("invalid --provider %s", o.Flags.Provider)
+	}
+}
+
+// renderResult logs the outcome of the command in text mode, or writes a CloudServiceAccountResult
+// as JSON/YAML to stdout in --output json/yaml mode so it can be consumed by CI
+func (o *CreateCloudServiceAccountOptions) renderResult(credential cloud.Credential, roles []string) error {
+	if o.Flags.Output == "text" {
+		if credential.KeyPath != "" {
+			log.Infof("Created service account key %s\n", util.ColorInfo(credential.KeyPath))
+		} else {
+			log.Infof("Created service account %s\n", util.ColorInfo(credential.Identifier))
+		}
+		return nil
+	}
+
+	result := CloudServiceAccountResult{
+		Provider:   o.Flags.Provider,
+		Identifier: credential.Identifier,
+		KeyPath:    credential.KeyPath,
+		Account:    o.Flags.Account,
+		Roles:      roles,
+	}
+
+	var data []byte
+	var err error
+	if o.Flags.Output == "json" {
+		data, err = json.MarshalIndent(result, "", "  ")
+	} else {
+		data, err = yaml.Marshal(result)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.Out, string(data))
+	return nil
+}
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"io"
+	"os"
+	"strings"
+	"time"
 
 	"errors"
 	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/jenkins-x/jx/pkg/cloud"
 	"github.com/jenkins-x/jx/pkg/cloud/gke"
 	"github.com/jenkins-x/jx/pkg/jx/cmd/templates"
 	"github.com/jenkins-x/jx/pkg/log"
@@ -13,10 +19,54 @@ import (
 	"gopkg.in/AlecAivazis/survey.v1"
 )
 
+// validGkeServiceAccountOutputs are the output formats supported by --output
+var validGkeServiceAccountOutputs = []string{"text", "json", "yaml"}
+
+// validGkeServiceAccountKeyTypes are the credential types supported by --key-type
+var validGkeServiceAccountKeyTypes = []string{"json", "p12", "workload-identity", "none"}
+
+// GkeServiceAccountResult is emitted on stdout when --output json or --output yaml is used
+type GkeServiceAccountResult struct {
+	KeyPath             string   `json:"keyPath"`
+	ServiceAccountEmail string   `json:"serviceAccountEmail"`
+	ProjectID           string   `json:"projectId"`
+	Roles               []string `json:"roles"`
+}
+
 type CreateGkeServiceAccountFlags struct {
-	Name      string
-	Project   string
-	SkipLogin bool
+	Name         string
+	Project      string
+	SkipLogin    bool
+	IfNotExists  bool
+	Roles        []string
+	RolesPreset  string
+	Batch        bool
+	Output       string
+	KeyType      string
+	K8sNamespace string
+	K8sSA        string
+	KeyRotate    bool
+	KeyMaxAge    time.Duration
+	KmsKey       string
+	AgeRecipient string
+}
+
+// rolesPresets are curated sets of IAM roles that can be bound to a service account via
+// --roles-preset instead of having to pass each --role individually
+var rolesPresets = map[string][]string{
+	"jx-minimal": {
+		"roles/container.developer",
+		"roles/storage.admin",
+	},
+	"jx-full": {
+		"roles/container.admin",
+		"roles/storage.admin",
+		"roles/iam.serviceAccountUser",
+		"roles/compute.admin",
+	},
+	"kaniko": {
+		"roles/storage.admin",
+	},
 }
 
 type CreateGkeServiceAccountOptions struct {
@@ -31,6 +81,9 @@ var (
 		# to specify the options via flags
 		jx create gke-service-account --name my-service-account --project my-gke-project
 
+		# to run non-interactively from CI, emitting the result as JSON
+		jx create gke-service-account -b -n my-service-account -p my-gke-project --output json
+
 `)
 )
 
@@ -68,10 +121,43 @@ func (options *CreateGkeServiceAccountOptions) addFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&options.Flags.Name, "name", "n", "", "The name of the service account to create")
 	cmd.Flags().StringVarP(&options.Flags.Project, "project", "p", "", "The GCP project to create the service account in")
 	cmd.Flags().BoolVarP(&options.Flags.SkipLogin, "skip-login", "", false, "Skip Google auth if already logged in via gloud auth")
+	cmd.Flags().BoolVarP(&options.Flags.IfNotExists, "if-not-exists", "", true, "Reuse the service account if one with the given name already exists instead of failing")
+	cmd.Flags().StringArrayVarP(&options.Flags.Roles, "role", "", nil, "The IAM roles to bind to the service account, e.g. roles/container.admin. Can be repeated")
+	cmd.Flags().StringVarP(&options.Flags.RolesPreset, "roles-preset", "", "", "A curated set of IAM roles to bind to the service account: jx-minimal, jx-full or kaniko")
+	cmd.Flags().BoolVarP(&options.Flags.Batch, "batch", "b", false, "Runs in batch mode, failing fast instead of prompting interactively when required flags are missing")
+	cmd.Flags().BoolVarP(&options.Flags.Batch, "non-interactive", "", false, "Equivalent to --batch")
+	cmd.Flags().StringVarP(&options.Flags.Output, "output", "o", "text", "The output format of the created service account details: text, json or yaml")
+	cmd.Flags().StringVarP(&options.Flags.KeyType, "key-type", "", "json", "The type of credential to create: json, p12, workload-identity or none")
+	cmd.Flags().StringVarP(&options.Flags.K8sNamespace, "k8s-namespace", "", "", "The Kubernetes namespace of the Kubernetes service account to bind, used with --key-type workload-identity")
+	cmd.Flags().StringVarP(&options.Flags.K8sSA, "k8s-sa", "", "", "The Kubernetes service account to bind, used with --key-type workload-identity")
+	cmd.Flags().BoolVarP(&options.Flags.KeyRotate, "key-rotate", "", false, "Delete keys older than --key-max-age before minting a new one")
+	cmd.Flags().DurationVarP(&options.Flags.KeyMaxAge, "key-max-age", "", 90*24*time.Hour, "The maximum age of a service account key before --key-rotate deletes it")
+	cmd.Flags().StringVarP(&options.Flags.KmsKey, "kms-key", "", "", "A Cloud KMS key resource name used to encrypt the downloaded key, e.g. projects/p/locations/l/keyRings/r/cryptoKeys/k")
+	cmd.Flags().StringVarP(&options.Flags.AgeRecipient, "age-recipient", "", "", "An age public key recipient used to encrypt the downloaded key")
 }
 
 // Run implements this command
 func (o *CreateGkeServiceAccountOptions) Run() error {
+	if os.Getenv("JX_BATCH") == "1" {
+		o.Flags.Batch = true
+	}
+
+	if !util.Contains(validGkeServiceAccountOutputs, o.Flags.Output) {
+		return fmt.Errorf("invalid --output %s, must be one of %s", o.Flags.Output, strings.Join(validGkeServiceAccountOutputs, ", "))
+	}
+
+	if !util.Contains(validGkeServiceAccountKeyTypes, o.Flags.KeyType) {
+		return fmt.Errorf("invalid --key-type %s, must be one of %s", o.Flags.KeyType, strings.Join(validGkeServiceAccountKeyTypes, ", "))
+	}
+
+	if o.Flags.KeyType == "workload-identity" && (o.Flags.K8sNamespace == "" || o.Flags.K8sSA == "") {
+		return errors.New("--k8s-namespace and --k8s-sa are required with --key-type workload-identity")
+	}
+
+	if o.Flags.KmsKey != "" && o.Flags.AgeRecipient != "" {
+		return errors.New("only one of --kms-key or --age-recipient may be specified")
+	}
+
 	if !o.Flags.SkipLogin {
 		err := o.runCommandVerbose("gcloud", "auth", "login", "--brief")
 		if err != nil {
@@ -80,6 +166,10 @@ func (o *CreateGkeServiceAccountOptions) Run() error {
 	}
 
 	if o.Flags.Name == "" {
+		if o.Flags.Batch {
+			return errors.New("--name must be specified in batch mode")
+		}
+
 		prompt := &survey.Input{
 			Message: "Name for the service account",
 		}
@@ -99,6 +189,10 @@ func (o *CreateGkeServiceAccountOptions) Run() error {
 	}
 
 	if o.Flags.Project == "" {
+		if o.Flags.Batch {
+			return errors.New("--project must be specified in batch mode")
+		}
+
 		projectId, err := o.getGoogleProjectId()
 		if err != nil {
 			return err
@@ -106,16 +200,95 @@ func (o *CreateGkeServiceAccountOptions) Run() error {
 		o.Flags.Project = projectId
 	}
 
-	path, err := gke.GetOrCreateServiceAccount(o.Flags.Name, o.Flags.Project, util.HomeDir())
+	roles, err := o.serviceAccountRoles()
+	if err != nil {
+		return err
+	}
+
+	// jx create gke-service-account is a thin alias over the gke implementation of the generic
+	// cloud.ServiceAccountProvider also driven by "jx create cloud-service-account --provider gke"
+	provider := gke.NewProvider()
+	spec := cloud.ServiceAccountSpec{
+		Name:              o.Flags.Name,
+		Account:           o.Flags.Project,
+		IfNotExists:       o.Flags.IfNotExists,
+		Roles:             roles,
+		KeyType:           o.Flags.KeyType,
+		K8sNamespace:      o.Flags.K8sNamespace,
+		K8sServiceAccount: o.Flags.K8sSA,
+		ForceNewKey:       o.Flags.KeyRotate,
+		KmsKey:            o.Flags.KmsKey,
+		AgeRecipient:      o.Flags.AgeRecipient,
+	}
+
+	credential, err := cloud.CreateServiceAccount(provider, spec, o.Flags.KeyRotate, o.Flags.KeyMaxAge, util.HomeDir())
 	if err != nil {
 		return err
 	}
 
-	log.Infof("Created service account key %s\n", util.ColorInfo(path))
+	return o.renderResult(credential.KeyPath, roles)
+}
+
+// renderResult logs the created service account email or key path in text mode, or writes a
+// GkeServiceAccountResult as JSON/YAML to stdout in --output json/yaml mode
+func (o *CreateGkeServiceAccountOptions) renderResult(keyPath string, roles []string) error {
+	email := gke.ServiceAccountEmail(o.Flags.Name, o.Flags.Project)
 
+	if o.Flags.Output == "text" {
+		if keyPath != "" {
+			log.Infof("Created service account key %s\n", util.ColorInfo(keyPath))
+		} else {
+			log.Infof("Created service account %s\n", util.ColorInfo(email))
+		}
+		return nil
+	}
+
+	result := GkeServiceAccountResult{
+		KeyPath:             keyPath,
+		ServiceAccountEmail: email,
+		ProjectID:           o.Flags.Project,
+		Roles:               roles,
+	}
+
+	var data []byte
+	var err error
+	if o.Flags.Output == "json" {
+		data, err = json.MarshalIndent(result, "", "  ")
+	} else {
+		data, err = yaml.Marshal(result)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(o.Out, string(data))
 	return nil
 }
 
+// serviceAccountRoles returns the set of IAM roles to bind to the service account, combining
+// any --role flags with the roles expanded from --roles-preset
+func (o *CreateGkeServiceAccountOptions) serviceAccountRoles() ([]string, error) {
+	return resolveServiceAccountRoles(o.Flags.Roles, o.Flags.RolesPreset)
+}
+
+// resolveServiceAccountRoles combines explicit roles with the roles expanded from a
+// --roles-preset name, shared by both "create gke-service-account" and
+// "create cloud-service-account"; the presets are GCP-specific, so the latter rejects
+// --roles-preset for any provider other than gke
+func resolveServiceAccountRoles(roles []string, preset string) ([]string, error) {
+	resolved := append([]string{}, roles...)
+
+	if preset != "" {
+		presetRoles, ok := rolesPresets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown --roles-preset %s, must be one of jx-minimal, jx-full, kaniko", preset)
+		}
+		resolved = append(resolved, presetRoles...)
+	}
+
+	return resolved, nil
+}
+
 // asks to chose from existing projects or optionally creates one if none exist
 func (o *CreateGkeServiceAccountOptions) getGoogleProjectId() (string, error) {
 	existingProjects, err := gke.GetGoogleProjects()
@@ -138,9 +311,25 @@ func (o *CreateGkeServiceAccountOptions) getGoogleProjectId() (string, error) {
 			return "", errors.New("no google project to create cluster in, please manual create one and rerun this wizard")
 		}
 
-		if flag {
-			return "", errors.New("auto creating projects not yet implemented, please manually create one and rerun the wizard")
+		id := ""
+		namePrompt := &survey.Input{
+			Message: "Enter an ID for the new Google Cloud Project (also used as its name)",
+		}
+		err = survey.AskOne(namePrompt, &id, survey.Required)
+		if err != nil {
+			return "", err
+		}
+
+		billingAccount := ""
+		billingPrompt := &survey.Input{
+			Message: "Billing account to link to the new project (optional)",
 		}
+		err = survey.AskOne(billingPrompt, &billingAccount, nil)
+		if err != nil {
+			return "", err
+		}
+
+		return gke.CreateGoogleProject(id, id, billingAccount)
 	} else if len(existingProjects) == 1 {
 		projectId = existingProjects[0]
 		log.Infof("Using the only Google Cloud Project %s to create the cluster\n", util.ColorInfo(projectId))
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveServiceAccountRolesCombinesRolesAndPreset(t *testing.T) {
+	roles, err := resolveServiceAccountRoles([]string{"roles/custom.role"}, "kaniko")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"roles/custom.role", "roles/storage.admin"}
+	if !reflect.DeepEqual(roles, expected) {
+		t.Errorf("expected %v, got %v", expected, roles)
+	}
+}
+
+func TestResolveServiceAccountRolesNoPreset(t *testing.T) {
+	roles, err := resolveServiceAccountRoles([]string{"roles/custom.role"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"roles/custom.role"}
+	if !reflect.DeepEqual(roles, expected) {
+		t.Errorf("expected %v, got %v", expected, roles)
+	}
+}
+
+func TestResolveServiceAccountRolesUnknownPreset(t *testing.T) {
+	if _, err := resolveServiceAccountRoles(nil, "does-not-exist"); err == nil {
+		t.Error("expected an error for an unknown --roles-preset")
+	}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"errors"
+	"fmt"
+	"github.com/jenkins-x/jx/pkg/cloud"
+	"github.com/jenkins-x/jx/pkg/jx/cmd/templates"
+	"github.com/spf13/cobra"
+)
+
+type GetGkeServiceAccountKeyFlags struct {
+	File        string
+	KmsKey      string
+	AgeIdentity string
+	Decrypt     bool
+}
+
+type GetGkeServiceAccountKeyOptions struct {
+	CommonOptions
+	Flags GetGkeServiceAccountKeyFlags
+}
+
+var (
+	getGkeServiceAccountKeyExample = templates.Examples(`
+		# stream a KMS-encrypted key to stdout without it ever touching disk in cleartext
+		jx get gke-service-account-key --decrypt --file my-service-account.key.json.enc --kms-key projects/p/locations/l/keyRings/r/cryptoKeys/k
+
+		# stream an age-encrypted key to stdout
+		jx get gke-service-account-key --decrypt --file my-service-account.key.json.enc --age-identity ~/.age/key.txt
+
+`)
+)
+
+// NewCmdGetGkeServiceAccountKey creates a command object for the "get" command
+func NewCmdGetGkeServiceAccountKey(f Factory, out io.Writer, errOut io.Writer) *cobra.Command {
+	options := &GetGkeServiceAccountKeyOptions{
+		CommonOptions: CommonOptions{
+			Factory: f,
+			Out:     out,
+			Err:     errOut,
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:     "gke-service-account-key",
+		Short:   "Decrypts a GKE service account key encrypted by 'jx create gke-service-account'",
+		Example: getGkeServiceAccountKeyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			options.Cmd = cmd
+			options.Args = args
+			err := options.Run()
+			CheckErr(err)
+		},
+	}
+
+	options.addCommonFlags(cmd)
+	options.addFlags(cmd)
+
+	return cmd
+}
+
+func (options *GetGkeServiceAccountKeyOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&options.Flags.File, "file", "f", "", "The path to the encrypted service account key, e.g. my-service-account.key.json.enc")
+	cmd.Flags().StringVarP(&options.Flags.KmsKey, "kms-key", "", "", "The Cloud KMS key resource name the file was encrypted with")
+	cmd.Flags().StringVarP(&options.Flags.AgeIdentity, "age-identity", "", "", "The path to the age identity (private key) file to decrypt with")
+	cmd.Flags().BoolVarP(&options.Flags.Decrypt, "decrypt", "", false, "Decrypt the key and stream the plaintext to stdout")
+}
+
+// Run implements this command
+func (o *GetGkeServiceAccountKeyOptions) Run() error {
+	if !o.Flags.Decrypt {
+		return errors.New("--decrypt must be specified")
+	}
+	if o.Flags.File == "" {
+		return fmt.Errorf("--file must be specified")
+	}
+
+	return cloud.DecryptKeyFile(o.Flags.File, o.Flags.KmsKey, o.Flags.AgeIdentity, os.Stdout)
+}